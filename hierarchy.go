@@ -0,0 +1,15 @@
+package mtree
+
+// Entry is a single file, directory, or symlink record inside a
+// DirectoryHierarchy, together with the keyword values ("size",
+// "sha256digest", "mode", ...) that were collected for it.
+type Entry struct {
+	Name     string
+	Keywords map[string]string
+}
+
+// DirectoryHierarchy is an in-memory mtree manifest: an ordered list of
+// Entry records, one per path that was walked or parsed.
+type DirectoryHierarchy struct {
+	Entries []Entry
+}
@@ -0,0 +1,219 @@
+package mtree
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"go.crypto/ripemd160"
+)
+
+// hasherSpec describes one of the hash-family keywords that EvalKeywords
+// can fan out across a single io.Copy instead of re-reading the file once
+// per keyword. digestName is the value that ends up on the left of "=" in
+// the formatted output, matching what HasherKeywordFunc already produces.
+type hasherSpec struct {
+	digestName string
+	newHash    func() hash.Hash
+}
+
+// hasherSpecs lists every keyword in KeywordFuncs that is backed by a
+// hash.Hash digest of the file payload. It mirrors the HasherKeywordFunc
+// entries in the KeywordFuncs map in keywordfuncs.go; keep the two in sync. It is only
+// ever consulted for keywords LookupKeyword still reports as registered, so
+// UnregisterKeyword("sha256") (etc.) stops EvalKeywords from emitting that
+// digest too, not just direct KeywordFunc calls. Digest keywords registered
+// later via RegisterKeyword aren't in this table, so they fall through to
+// the generic path below and still get a real reader, just without the
+// multi-digest fan-out.
+var hasherSpecs = map[string]hasherSpec{
+	"md5":             {"md5digest", md5.New},
+	"md5digest":       {"md5digest", md5.New},
+	"rmd160":          {"ripemd160digest", ripemd160.New},
+	"rmd160digest":    {"ripemd160digest", ripemd160.New},
+	"ripemd160digest": {"ripemd160digest", ripemd160.New},
+	"sha1":            {"sha1digest", sha1.New},
+	"sha1digest":      {"sha1digest", sha1.New},
+	"sha256":          {"sha256digest", sha256.New},
+	"sha256digest":    {"sha256digest", sha256.New},
+	"sha384":          {"sha384digest", sha512.New384},
+	"sha384digest":    {"sha384digest", sha512.New384},
+	"sha512":          {"sha512digest", sha512.New},
+	"sha512digest":    {"sha512digest", sha512.New},
+}
+
+// EvalKeywords evaluates keywords for a single file, the same way that
+// calling each entry of KeywordFuncs in turn would, except that every
+// hash-family keyword (plus cksum) is computed from one io.Copy fanned out
+// through an io.MultiWriter instead of one io.Copy per keyword. This means
+// requesting all seven digest keywords for the same file costs one read of
+// its content, not seven. Neither pass buffers the file in memory: cksum
+// is fed through an io.Pipe, so it and any hash digests are driven by the
+// same io.Copy.
+//
+// opener is called at most twice per entry: once for the hash/cksum
+// fan-out above, and once more, shared across every other requested
+// keyword, for the rest of the set (evalRest seeks that single reader back
+// to the start between calls instead of reopening per keyword). Neither open
+// happens for a file that is not regular; for a regular file, any keyword
+// outside the hash/cksum set, even one that only consults path/info (e.g.
+// "type", "mode"), causes the second, shared open. Keywords with no
+// registered KeywordFunc are silently skipped.
+func EvalKeywords(path string, info os.FileInfo, opener func() (io.ReadCloser, error), keywords []string) (map[string]string, error) {
+	out := make(map[string]string, len(keywords))
+
+	hashersByDigest := make(map[string]func() hash.Hash)
+	aliasesByDigest := make(map[string][]string)
+	needCksum := false
+	var rest []string
+	for _, kw := range keywords {
+		if _, registered := LookupKeyword(kw); !registered {
+			// Not in the registry (never existed, or UnregisterKeyword'd
+			// away): don't let the static fast-path tables below resurrect
+			// it. Falling through to rest's LookupKeyword means it's simply
+			// dropped from the output, same as any other unknown keyword.
+			rest = append(rest, kw)
+			continue
+		}
+		switch {
+		case kw == "cksum":
+			needCksum = true
+		default:
+			if spec, ok := hasherSpecs[kw]; ok {
+				hashersByDigest[spec.digestName] = spec.newHash
+				aliasesByDigest[spec.digestName] = append(aliasesByDigest[spec.digestName], kw)
+				continue
+			}
+			rest = append(rest, kw)
+		}
+	}
+
+	if (len(hashersByDigest) > 0 || needCksum) && info.Mode().IsRegular() {
+		rc, err := opener()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		writers := make([]io.Writer, 0, len(hashersByDigest)+1)
+		hashes := make(map[string]hash.Hash, len(hashersByDigest))
+		for digestName, newHash := range hashersByDigest {
+			h := newHash()
+			hashes[digestName] = h
+			writers = append(writers, h)
+		}
+
+		// cksum's signature takes a reader and reads it to completion
+		// itself, so whenever it's wanted, stream to it concurrently through
+		// a pipe (one more writer in the fan-out) instead of buffering the
+		// whole file just to hand it a second reader.
+		var (
+			pw        *io.PipeWriter
+			cksumDone chan struct{}
+			cksumSum  uint32
+			cksumErr  error
+		)
+		if needCksum {
+			var pr *io.PipeReader
+			pr, pw = io.Pipe()
+			writers = append(writers, pw)
+
+			cksumDone = make(chan struct{})
+			go func() {
+				defer close(cksumDone)
+				cksumSum, _, cksumErr = cksum(pr)
+				io.Copy(ioutil.Discard, pr)
+			}()
+		}
+
+		_, copyErr := io.Copy(io.MultiWriter(writers...), rc)
+		if pw != nil {
+			pw.CloseWithError(copyErr)
+			<-cksumDone
+		}
+		if copyErr != nil {
+			return nil, copyErr
+		}
+		if needCksum {
+			if cksumErr != nil {
+				return nil, cksumErr
+			}
+			out["cksum"] = fmt.Sprintf("cksum=%d", cksumSum)
+		}
+
+		for digestName, h := range hashes {
+			sum := fmt.Sprintf("%s=%x", digestName, h.Sum(nil))
+			for _, alias := range aliasesByDigest[digestName] {
+				out[alias] = sum
+			}
+		}
+	}
+
+	if len(rest) > 0 {
+		vals, err := evalRest(rest, path, info, opener)
+		if err != nil {
+			return nil, err
+		}
+		for kw, val := range vals {
+			out[kw] = val
+		}
+	}
+
+	return out, nil
+}
+
+// evalRest runs every keyword in rest against a single opened reader,
+// seeking it back to the start between calls, instead of calling opener
+// once per keyword. Most of rest (e.g. "mode", "type", "uid") never touches
+// the reader at all, but the KeywordFunc signature doesn't say so up front,
+// so the only way to know is to open once and let whichever ones actually
+// read it share that one read. Keywords with no registered KeywordFunc are
+// silently skipped, same as EvalKeywords' own doc comment promises.
+func evalRest(rest []string, path string, info os.FileInfo, opener func() (io.ReadCloser, error)) (map[string]string, error) {
+	out := make(map[string]string, len(rest))
+
+	var rc io.ReadCloser
+	var seeker io.Seeker
+	if info.Mode().IsRegular() && opener != nil {
+		var err error
+		rc, err = opener()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		seeker, _ = rc.(io.Seeker)
+	}
+
+	for _, kw := range rest {
+		fn, ok := LookupKeyword(kw)
+		if !ok {
+			continue
+		}
+
+		var r io.Reader
+		if rc != nil {
+			if seeker != nil {
+				if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+					return nil, err
+				}
+			}
+			r = rc
+		}
+
+		val, err := fn(path, info, r)
+		if err != nil {
+			return nil, err
+		}
+		if val != "" {
+			out[kw] = val
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,146 @@
+package mtree
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func openerFor(data []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+// countingOpener wraps an *os.File-backed opener (so the reader it hands
+// back is seekable) and counts how many times it's actually called.
+func countingOpener(t *testing.T, path string) (func() (io.ReadCloser, error), *int) {
+	t.Helper()
+	calls := 0
+	return func() (io.ReadCloser, error) {
+		calls++
+		return os.Open(path)
+	}, &calls
+}
+
+// TestEvalKeywordsMultiDigest checks that requesting several hash-family
+// keywords for the same file produces the same digests HasherKeywordFunc
+// would, fanned out across one io.Copy rather than one per keyword.
+func TestEvalKeywordsMultiDigest(t *testing.T) {
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	info := fakeFileInfo{name: "file"}
+
+	vals, err := EvalKeywords("file", info, openerFor(data), []string{"sha256", "md5"})
+	if err != nil {
+		t.Fatalf("EvalKeywords: %v", err)
+	}
+
+	wantSha256 := fmt.Sprintf("sha256digest=%x", sha256.Sum256(data))
+	if vals["sha256"] != wantSha256 {
+		t.Errorf("sha256 = %q, want %q", vals["sha256"], wantSha256)
+	}
+	if vals["md5"] == "" {
+		t.Errorf("md5 missing from EvalKeywords result")
+	}
+}
+
+// TestEvalKeywordsCksumWithDigest exercises the io.Pipe path: cksum is
+// requested alongside a hash digest, so both must be driven by the same
+// io.Copy over the same data instead of racing or deadlocking.
+func TestEvalKeywordsCksumWithDigest(t *testing.T) {
+	data := []byte("some file content for cksum and sha1 together")
+	info := fakeFileInfo{name: "file"}
+
+	vals, err := EvalKeywords("file", info, openerFor(data), []string{"cksum", "sha1"})
+	if err != nil {
+		t.Fatalf("EvalKeywords: %v", err)
+	}
+
+	wantSum, _, err := cksum(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("cksum: %v", err)
+	}
+	if want := fmt.Sprintf("cksum=%d", wantSum); vals["cksum"] != want {
+		t.Errorf("cksum = %q, want %q", vals["cksum"], want)
+	}
+	if vals["sha1"] == "" {
+		t.Errorf("sha1 missing from EvalKeywords result")
+	}
+}
+
+// TestEvalKeywordsSharesReaderAcrossRest checks that requesting several
+// non-hash keywords for one regular file opens it once, not once per
+// keyword, and that a keyword which does read the payload (not just
+// path/info) still sees the whole file, seeked back to the start rather
+// than picking up where a previous keyword in the same pass left off.
+func TestEvalKeywordsSharesReaderAcrossRest(t *testing.T) {
+	data := []byte("shared reader payload")
+	f, err := ioutil.TempFile("", "evalkeywords")
+	if err != nil {
+		t.Fatalf("TempFile: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	f.Close()
+
+	const readAllKw = "x-test-readall"
+	if err := RegisterKeyword(readAllKw, func(path string, info os.FileInfo, r io.Reader) (string, error) {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s=%d", readAllKw, len(b)), nil
+	}); err != nil {
+		t.Fatalf("RegisterKeyword: %v", err)
+	}
+	defer UnregisterKeyword(readAllKw)
+
+	info, err := os.Stat(f.Name())
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+
+	opener, calls := countingOpener(t, f.Name())
+	vals, err := EvalKeywords(f.Name(), info, opener, []string{"mode", "type", readAllKw, readAllKw})
+	if err != nil {
+		t.Fatalf("EvalKeywords: %v", err)
+	}
+
+	if *calls != 1 {
+		t.Errorf("opener called %d times for 4 non-hash keywords, want 1", *calls)
+	}
+	if want := fmt.Sprintf("%s=%d", readAllKw, len(data)); vals[readAllKw] != want {
+		t.Errorf("%s = %q, want %q (reader wasn't reset between keywords)", readAllKw, vals[readAllKw], want)
+	}
+}
+
+// TestEvalKeywordsHonorsUnregister guards against EvalKeywords falling back
+// to its static hasherSpecs table once a hash-family keyword has been
+// UnregisterKeyword'd: the registry, not the table, must be the source of
+// truth for the one entry point Walk/NewFromTar drive.
+func TestEvalKeywordsHonorsUnregister(t *testing.T) {
+	UnregisterKeyword("sha256")
+	defer func() {
+		keywordFuncsMu.Lock()
+		keywordFuncs["sha256"] = KeywordFuncs["sha256"]
+		keywordFuncsMu.Unlock()
+	}()
+
+	if _, ok := LookupKeyword("sha256"); ok {
+		t.Fatalf("sha256 still registered after UnregisterKeyword")
+	}
+
+	vals, err := EvalKeywords("file", fakeFileInfo{name: "file"}, openerFor([]byte("content")), []string{"sha256"})
+	if err != nil {
+		t.Fatalf("EvalKeywords: %v", err)
+	}
+	if _, ok := vals["sha256"]; ok {
+		t.Fatalf("EvalKeywords returned sha256 = %q for an unregistered keyword", vals["sha256"])
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"hash"
 	"io"
 	"os"
+	"sync"
 
 	"go.crypto/ripemd160"
 )
@@ -22,31 +23,38 @@ import (
 // for each new KeywordFunc
 type KeywordFunc func(path string, info os.FileInfo, r io.Reader) (string, error)
 
-// KeywordFuncs is the map of all keywords (and the functions to produce them)
+// KeywordFuncs is the built-in set of keywords (and the functions to produce
+// them). It is never mutated; RegisterKeyword and UnregisterKeyword operate
+// on a separate copy so that this map always reflects what the package
+// supports out of the box, and DefaultKeywordFuncs can hand back a clean
+// snapshot of it.
 var KeywordFuncs = map[string]KeywordFunc{
 	"size":            sizeKeywordFunc,                                     // The size, in bytes, of the file
 	"type":            typeKeywordFunc,                                     // The type of the file
 	"time":            timeKeywordFunc,                                     // The last modification time of the file
+	"atime":           atimeKeywordFunc,                                    // The last access time of the file, where supported
+	"ctime":           ctimeKeywordFunc,                                    // The last inode status change time of the file, where supported
 	"link":            linkKeywordFunc,                                     // The target of the symbolic link when type=link
 	"uid":             uidKeywordFunc,                                      // The file owner as a numeric value
 	"gid":             gidKeywordFunc,                                      // The file group as a numeric value
 	"nlink":           nlinkKeywordFunc,                                    // The number of hard links the file is expected to have
 	"uname":           unameKeywordFunc,                                    // The file owner as a symbolic name
+	"gname":           gnameKeywordFunc,                                    // The file group as a symbolic name, where supported
 	"mode":            modeKeywordFunc,                                     // The current file's permissions as a numeric (octal) or symbolic value
 	"cksum":           cksumKeywordFunc,                                    // The checksum of the file using the default algorithm specified by the cksum(1) utility
-	"md5":             hasherKeywordFunc("md5digest", md5.New),             // The MD5 message digest of the file
-	"md5digest":       hasherKeywordFunc("md5digest", md5.New),             // A synonym for `md5`
-	"rmd160":          hasherKeywordFunc("ripemd160digest", ripemd160.New), // The RIPEMD160 message digest of the file
-	"rmd160digest":    hasherKeywordFunc("ripemd160digest", ripemd160.New), // A synonym for `rmd160`
-	"ripemd160digest": hasherKeywordFunc("ripemd160digest", ripemd160.New), // A synonym for `rmd160`
-	"sha1":            hasherKeywordFunc("sha1digest", sha1.New),           // The SHA1 message digest of the file
-	"sha1digest":      hasherKeywordFunc("sha1digest", sha1.New),           // A synonym for `sha1`
-	"sha256":          hasherKeywordFunc("sha256digest", sha256.New),       // The SHA256 message digest of the file
-	"sha256digest":    hasherKeywordFunc("sha256digest", sha256.New),       // A synonym for `sha256`
-	"sha384":          hasherKeywordFunc("sha384digest", sha512.New384),    // The SHA384 message digest of the file
-	"sha384digest":    hasherKeywordFunc("sha384digest", sha512.New384),    // A synonym for `sha384`
-	"sha512":          hasherKeywordFunc("sha512digest", sha512.New),       // The SHA512 message digest of the file
-	"sha512digest":    hasherKeywordFunc("sha512digest", sha512.New),       // A synonym for `sha512`
+	"md5":             HasherKeywordFunc("md5digest", md5.New),             // The MD5 message digest of the file
+	"md5digest":       HasherKeywordFunc("md5digest", md5.New),             // A synonym for `md5`
+	"rmd160":          HasherKeywordFunc("ripemd160digest", ripemd160.New), // The RIPEMD160 message digest of the file
+	"rmd160digest":    HasherKeywordFunc("ripemd160digest", ripemd160.New), // A synonym for `rmd160`
+	"ripemd160digest": HasherKeywordFunc("ripemd160digest", ripemd160.New), // A synonym for `rmd160`
+	"sha1":            HasherKeywordFunc("sha1digest", sha1.New),           // The SHA1 message digest of the file
+	"sha1digest":      HasherKeywordFunc("sha1digest", sha1.New),           // A synonym for `sha1`
+	"sha256":          HasherKeywordFunc("sha256digest", sha256.New),       // The SHA256 message digest of the file
+	"sha256digest":    HasherKeywordFunc("sha256digest", sha256.New),       // A synonym for `sha256`
+	"sha384":          HasherKeywordFunc("sha384digest", sha512.New384),    // The SHA384 message digest of the file
+	"sha384digest":    HasherKeywordFunc("sha384digest", sha512.New384),    // A synonym for `sha384`
+	"sha512":          HasherKeywordFunc("sha512digest", sha512.New),       // The SHA512 message digest of the file
+	"sha512digest":    HasherKeywordFunc("sha512digest", sha512.New),       // A synonym for `sha512`
 
 	// This is not an upstreamed keyword, but used to vary from "time", as tar
 	// archives do not store nanosecond precision. So comparing on "time" will
@@ -60,6 +68,71 @@ var KeywordFuncs = map[string]KeywordFunc{
 	"xattr":  xattrKeywordFunc,
 	"xattrs": xattrKeywordFunc,
 }
+
+var (
+	keywordFuncsMu sync.RWMutex
+	keywordFuncs   = cloneKeywordFuncs(KeywordFuncs)
+)
+
+// RegisteredKeywordFuncs returns a snapshot of every keyword currently
+// registered, built-in and third-party alike, reflecting any
+// RegisterKeyword/UnregisterKeyword calls made so far. Callers that only
+// need to look up a single keyword should prefer LookupKeyword, which avoids
+// the copy.
+func RegisteredKeywordFuncs() map[string]KeywordFunc {
+	keywordFuncsMu.RLock()
+	defer keywordFuncsMu.RUnlock()
+	return cloneKeywordFuncs(keywordFuncs)
+}
+
+// LookupKeyword returns the KeywordFunc registered for name, and whether one
+// was found. It consults the same registry that RegisterKeyword and
+// UnregisterKeyword modify.
+func LookupKeyword(name string) (KeywordFunc, bool) {
+	keywordFuncsMu.RLock()
+	defer keywordFuncsMu.RUnlock()
+	fn, ok := keywordFuncs[name]
+	return fn, ok
+}
+
+// RegisterKeyword adds fn as the KeywordFunc for name, so that third-party
+// packages (container image tooling wanting BLAKE2b/BLAKE3/SHA3 digests, or
+// domain-specific attributes) can extend the keyword set without forking
+// this package. It returns an error if name is already registered, so a
+// plugin can't silently shadow a built-in keyword (or another plugin's).
+func RegisterKeyword(name string, fn KeywordFunc) error {
+	keywordFuncsMu.Lock()
+	defer keywordFuncsMu.Unlock()
+	if _, ok := keywordFuncs[name]; ok {
+		return fmt.Errorf("mtree: keyword %q is already registered", name)
+	}
+	keywordFuncs[name] = fn
+	return nil
+}
+
+// UnregisterKeyword removes name from the registry. It is a no-op if name
+// isn't registered.
+func UnregisterKeyword(name string) {
+	keywordFuncsMu.Lock()
+	defer keywordFuncsMu.Unlock()
+	delete(keywordFuncs, name)
+}
+
+// DefaultKeywordFuncs returns a fresh copy of the built-in keyword set,
+// unaffected by any RegisterKeyword/UnregisterKeyword calls made since
+// package initialization.
+func DefaultKeywordFuncs() map[string]KeywordFunc {
+	return cloneKeywordFuncs(KeywordFuncs)
+}
+
+func cloneKeywordFuncs(m map[string]KeywordFunc) map[string]KeywordFunc {
+	out := make(map[string]KeywordFunc, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
 var (
 	modeKeywordFunc = func(path string, info os.FileInfo, r io.Reader) (string, error) {
 		permissions := info.Mode().Perm()
@@ -92,27 +165,12 @@ var (
 		}
 		return fmt.Sprintf("cksum=%d", sum), nil
 	}
-	hasherKeywordFunc = func(name string, newHash func() hash.Hash) KeywordFunc {
-		return func(path string, info os.FileInfo, r io.Reader) (string, error) {
-			if !info.Mode().IsRegular() {
-				return "", nil
-			}
-			h := newHash()
-			if _, err := io.Copy(h, r); err != nil {
-				return "", err
-			}
-			return fmt.Sprintf("%s=%x", name, h.Sum(nil)), nil
-		}
-	}
 	tartimeKeywordFunc = func(path string, info os.FileInfo, r io.Reader) (string, error) {
 		return fmt.Sprintf("tar_time=%d.000000000", info.ModTime().Unix()), nil
 	}
 	timeKeywordFunc = func(path string, info os.FileInfo, r io.Reader) (string, error) {
-		t := info.ModTime().UnixNano()
-		if t == 0 {
-			return "time=0.000000000", nil
-		}
-		return fmt.Sprintf("time=%d.%9.9d", (t / 1e9), (t % (t / 1e9))), nil
+		sec, nsec := splitUnixNano(info.ModTime().UnixNano())
+		return fmt.Sprintf("time=%d.%09d", sec, nsec), nil
 	}
 	linkKeywordFunc = func(path string, info os.FileInfo, r io.Reader) (string, error) {
 		if sys, ok := info.Sys().(*tar.Header); ok {
@@ -156,3 +214,35 @@ var (
 		return "", nil
 	}
 )
+
+// splitUnixNano splits a UnixNano() value into whole seconds and a
+// nanosecond remainder in [0, 1e9), the way BSD/FreeBSD mtree and
+// libarchive format "time" and "atime"/"ctime". A plain t/1e9, t%1e9 leaves
+// nsec negative for times before 1970, so it's nudged back into range.
+func splitUnixNano(t int64) (sec, nsec int64) {
+	sec, nsec = t/1e9, t%1e9
+	if nsec < 0 {
+		sec--
+		nsec += 1e9
+	}
+	return sec, nsec
+}
+
+// HasherKeywordFunc builds a KeywordFunc that reports the hex-encoded digest
+// of a regular file's content under the given name, using newHash for the
+// digest algorithm (e.g. sha1.New, or a third-party BLAKE2b/BLAKE3/SHA3
+// constructor). It's exported so that packages calling RegisterKeyword can
+// add their own digest keywords the same way the built-in md5/sha1/sha256/
+// sha384/sha512/rmd160 keywords are defined above.
+func HasherKeywordFunc(name string, newHash func() hash.Hash) KeywordFunc {
+	return func(path string, info os.FileInfo, r io.Reader) (string, error) {
+		if !info.Mode().IsRegular() {
+			return "", nil
+		}
+		h := newHash()
+		if _, err := io.Copy(h, r); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s=%x", name, h.Sum(nil)), nil
+	}
+}
@@ -0,0 +1,51 @@
+// +build linux
+
+package mtree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// atimeKeywordFunc and ctimeKeywordFunc expose the access and inode
+// status-change times that BSD mtree reports alongside "time", formatted
+// the same seconds.nanoseconds way. Both read info.Sys() as a
+// *syscall.Stat_t, so they only produce a value on platforms where that
+// assertion holds; elsewhere they're silently empty, like xattrKeywordFunc.
+//
+// gnameKeywordFunc fills the same role for the file's group, the way
+// unameKeywordFunc does for its owner, but as a distinct keyword since
+// group and owner names don't round-trip through "uname" alone.
+var (
+	atimeKeywordFunc = func(path string, info os.FileInfo, r io.Reader) (string, error) {
+		sys, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return "", nil
+		}
+		sec, nsec := splitUnixNano(int64(sys.Atim.Sec)*1e9 + int64(sys.Atim.Nsec))
+		return fmt.Sprintf("atime=%d.%09d", sec, nsec), nil
+	}
+	ctimeKeywordFunc = func(path string, info os.FileInfo, r io.Reader) (string, error) {
+		sys, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return "", nil
+		}
+		sec, nsec := splitUnixNano(int64(sys.Ctim.Sec)*1e9 + int64(sys.Ctim.Nsec))
+		return fmt.Sprintf("ctime=%d.%09d", sec, nsec), nil
+	}
+	gnameKeywordFunc = func(path string, info os.FileInfo, r io.Reader) (string, error) {
+		sys, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return "", nil
+		}
+		grp, err := user.LookupGroupId(strconv.FormatUint(uint64(sys.Gid), 10))
+		if err != nil {
+			return "", nil
+		}
+		return fmt.Sprintf("gname=%s", grp.Name), nil
+	}
+)
@@ -0,0 +1,24 @@
+// +build !linux
+
+package mtree
+
+import (
+	"io"
+	"os"
+)
+
+// atimeKeywordFunc, ctimeKeywordFunc, and gnameKeywordFunc are only
+// implemented where info.Sys() is known to yield a *syscall.Stat_t with
+// Atim/Ctim/Gid fields (see keywordfuncs_linux.go). Elsewhere they're left
+// empty rather than failing the whole walk over optional keywords.
+var (
+	atimeKeywordFunc = func(path string, info os.FileInfo, r io.Reader) (string, error) {
+		return "", nil
+	}
+	ctimeKeywordFunc = func(path string, info os.FileInfo, r io.Reader) (string, error) {
+		return "", nil
+	}
+	gnameKeywordFunc = func(path string, info os.FileInfo, r io.Reader) (string, error) {
+		return "", nil
+	}
+)
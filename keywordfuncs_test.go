@@ -0,0 +1,84 @@
+package mtree
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+// TestRegisterKeywordCollision guards the collision detection
+// RegisterKeyword promises: it must refuse to shadow a built-in keyword,
+// and refuse to shadow a previously registered plugin keyword too.
+func TestRegisterKeywordCollision(t *testing.T) {
+	noop := func(path string, info os.FileInfo, r io.Reader) (string, error) { return "", nil }
+
+	if err := RegisterKeyword("sha256", noop); err == nil {
+		t.Fatalf("RegisterKeyword(%q) over a built-in keyword did not error", "sha256")
+	}
+
+	const name = "x-test-keyword"
+	defer UnregisterKeyword(name)
+
+	if err := RegisterKeyword(name, noop); err != nil {
+		t.Fatalf("RegisterKeyword(%q): %v", name, err)
+	}
+	if err := RegisterKeyword(name, noop); err == nil {
+		t.Fatalf("RegisterKeyword(%q) a second time did not error", name)
+	}
+
+	if _, ok := LookupKeyword(name); !ok {
+		t.Fatalf("LookupKeyword(%q) = not found after RegisterKeyword", name)
+	}
+}
+
+// TestUnregisterKeyword checks that removing a keyword makes it
+// unavailable through LookupKeyword, and that re-registering it afterwards
+// works (UnregisterKeyword is meant to free the name up again).
+func TestUnregisterKeyword(t *testing.T) {
+	noop := func(path string, info os.FileInfo, r io.Reader) (string, error) { return "", nil }
+	const name = "x-test-keyword-2"
+
+	if err := RegisterKeyword(name, noop); err != nil {
+		t.Fatalf("RegisterKeyword(%q): %v", name, err)
+	}
+	UnregisterKeyword(name)
+	if _, ok := LookupKeyword(name); ok {
+		t.Fatalf("LookupKeyword(%q) still found after UnregisterKeyword", name)
+	}
+
+	if err := RegisterKeyword(name, noop); err != nil {
+		t.Fatalf("RegisterKeyword(%q) after Unregister: %v", name, err)
+	}
+	UnregisterKeyword(name)
+
+	// Unregistering an unknown name is a no-op, not an error.
+	UnregisterKeyword("x-never-registered")
+}
+
+// TestDefaultKeywordFuncsSnapshotIsolation checks that DefaultKeywordFuncs
+// hands back a copy unaffected by RegisterKeyword/UnregisterKeyword calls
+// made against the live registry, and that KeywordFuncs (the built-in set)
+// is itself untouched by them.
+func TestDefaultKeywordFuncsSnapshotIsolation(t *testing.T) {
+	before := len(DefaultKeywordFuncs())
+	builtinBefore := len(KeywordFuncs)
+
+	const name = "x-test-keyword-3"
+	noop := func(path string, info os.FileInfo, r io.Reader) (string, error) { return "", nil }
+	if err := RegisterKeyword(name, noop); err != nil {
+		t.Fatalf("RegisterKeyword(%q): %v", name, err)
+	}
+	defer UnregisterKeyword(name)
+
+	if got := len(DefaultKeywordFuncs()); got != before {
+		t.Fatalf("DefaultKeywordFuncs() len = %d after RegisterKeyword, want %d (unaffected)", got, before)
+	}
+	if got := len(KeywordFuncs); got != builtinBefore {
+		t.Fatalf("len(KeywordFuncs) = %d after RegisterKeyword, want %d (unaffected)", got, builtinBefore)
+	}
+
+	snap := RegisteredKeywordFuncs()
+	if _, ok := snap[name]; !ok {
+		t.Fatalf("RegisteredKeywordFuncs() missing %q after RegisterKeyword", name)
+	}
+}
@@ -0,0 +1,111 @@
+package mtree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Parse reads an mtree manifest — one entry per line, a path followed by
+// whitespace-separated "keyword=value" pairs, plus "/set" lines that add
+// defaults for every following entry (a later "/set" overrides only the
+// keywords it names, and "/unset" removes only the keywords it names) — into a
+// DirectoryHierarchy. Every keyword name encountered, on a "/set" line or an
+// entry line, is looked up against the live registry (LookupKeyword), so a
+// manifest written with a custom keyword added via RegisterKeyword parses
+// back cleanly as long as the reader has registered that same keyword;
+// Parse rejects anything it doesn't recognize rather than silently
+// swallowing it.
+func Parse(r io.Reader) (*DirectoryHierarchy, error) {
+	dh := &DirectoryHierarchy{}
+	set := map[string]string{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "/set":
+			if err := parseKeywordsInto(set, fields[1:]); err != nil {
+				return nil, err
+			}
+			continue
+		case "/unset":
+			for _, name := range fields[1:] {
+				delete(set, name)
+			}
+			continue
+		}
+
+		kws := make(map[string]string, len(set)+len(fields)-1)
+		for name, val := range set {
+			kws[name] = val
+		}
+		if err := parseKeywordsInto(kws, fields[1:]); err != nil {
+			return nil, err
+		}
+
+		dh.Entries = append(dh.Entries, Entry{
+			Name:     fields[0],
+			Keywords: kws,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return dh, nil
+}
+
+// parseKeywordsInto splits each "keyword=value" field and stores it in kws,
+// after checking the keyword name is registered (built-in or via
+// RegisterKeyword).
+func parseKeywordsInto(kws map[string]string, fields []string) error {
+	for _, kv := range fields {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 {
+			return fmt.Errorf("mtree: malformed keyword %q", kv)
+		}
+		name, val := kv[:i], kv[i+1:]
+		if _, ok := LookupKeyword(name); !ok {
+			return fmt.Errorf("mtree: unknown keyword %q (not registered; see RegisterKeyword)", name)
+		}
+		kws[name] = val
+	}
+	return nil
+}
+
+// WriteTo serializes dh as an mtree manifest: one line per Entry, with its
+// keywords written in sorted order so the output is deterministic. It
+// writes whatever keywords the Entry carries, built-in or from a
+// RegisterKeyword plugin alike — Parse is what enforces that the reader
+// recognizes them.
+func (dh *DirectoryHierarchy) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for _, e := range dh.Entries {
+		names := make([]string, 0, len(e.Keywords))
+		for name := range e.Keywords {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		line := e.Name
+		for _, name := range names {
+			line += fmt.Sprintf(" %s=%s", name, e.Keywords[name])
+		}
+		line += "\n"
+
+		n, err := io.WriteString(w, line)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,190 @@
+package mtree
+
+import (
+	"archive/tar"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// paxXattrPrefix is the PAX extended-header key prefix that GNU/pax tar
+// writers use to carry a file's extended attributes (SCHILY.xattr.<name>).
+const paxXattrPrefix = "SCHILY.xattr."
+
+// NewFromTar builds a DirectoryHierarchy by walking a tar stream directly,
+// without ever extracting it to disk. Each tar.Header is fed to
+// EvalKeywords via its own FileInfo (tar.Header.FileInfo already makes
+// info.Sys() yield the *tar.Header that sizeKeywordFunc and linkKeywordFunc
+// special-case), so this works for manifesting and verifying OCI/Docker
+// layer tarballs in place. "xattr"/"xattrs" (from PAX records) and
+// "uid"/"gid"/"uname"/"gname" (from the header's own ownership fields) are
+// filled in the same way, since nothing about a tar.Header satisfies the
+// *syscall.Stat_t type assertion those keywords otherwise rely on.
+//
+// Because tar headers only carry second-granularity mtimes, a requested
+// "time" keyword is evaluated as "tar_time" instead, to avoid claiming
+// nanosecond precision the archive never had.
+func NewFromTar(r io.Reader, keywords []string) (*DirectoryHierarchy, error) {
+	tr := tar.NewReader(r)
+	dh := &DirectoryHierarchy{}
+
+	tarKws := tarKeywords(keywords)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		info := hdr.FileInfo()
+		opener := func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(tr), nil
+		}
+
+		vals, err := EvalKeywords(hdr.Name, info, opener, tarKws)
+		if err != nil {
+			return nil, fmt.Errorf("mtree: %s: %v", hdr.Name, err)
+		}
+		for name, val := range xattrKeywordsFromPAX(hdr, keywords) {
+			vals[name] = val
+		}
+		for name, val := range ownershipKeywordsFromHeader(hdr, keywords) {
+			vals[name] = val
+		}
+
+		dh.Entries = append(dh.Entries, Entry{
+			Name:     hdr.Name,
+			Keywords: vals,
+		})
+	}
+
+	return dh, nil
+}
+
+// ValidateTar walks a tar stream the same way NewFromTar does, and reports
+// the names of any entries whose keyword values don't match what's recorded
+// in dh (typically produced by an earlier NewFromTar), including entries
+// present on only one side.
+func ValidateTar(r io.Reader, dh *DirectoryHierarchy, keywords []string) ([]string, error) {
+	want := make(map[string]Entry, len(dh.Entries))
+	for _, e := range dh.Entries {
+		want[e.Name] = e
+	}
+
+	got, err := NewFromTar(r, keywords)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatched []string
+	seen := make(map[string]bool, len(got.Entries))
+	for _, e := range got.Entries {
+		seen[e.Name] = true
+		wantEntry, ok := want[e.Name]
+		if !ok {
+			mismatched = append(mismatched, e.Name)
+			continue
+		}
+		mismatch := false
+		for kw, val := range e.Keywords {
+			if wantEntry.Keywords[kw] != val {
+				mismatch = true
+				break
+			}
+		}
+		if !mismatch {
+			for kw, val := range wantEntry.Keywords {
+				if e.Keywords[kw] != val {
+					mismatch = true
+					break
+				}
+			}
+		}
+		if mismatch {
+			mismatched = append(mismatched, e.Name)
+		}
+	}
+	for name := range want {
+		if !seen[name] {
+			mismatched = append(mismatched, name)
+		}
+	}
+
+	return mismatched, nil
+}
+
+// tarKeywords rewrites "time" to "tar_time" for tar semantics; every other
+// keyword passes through unchanged.
+func tarKeywords(keywords []string) []string {
+	out := make([]string, len(keywords))
+	for i, kw := range keywords {
+		if kw == "time" {
+			kw = "tar_time"
+		}
+		out[i] = kw
+	}
+	return out
+}
+
+// xattrKeywordsFromPAX synthesizes "xattr.<namespace.key>=<sha1>" values
+// from a tar header's PAX extended records, in the same format
+// xattrKeywordFunc produces for on-disk files: the SHA1 digest of the
+// attribute's value, not the value itself, so ordering and contents don't
+// affect comparison.
+func xattrKeywordsFromPAX(hdr *tar.Header, keywords []string) map[string]string {
+	if !wantsXattr(keywords) || len(hdr.PAXRecords) == 0 {
+		return nil
+	}
+	out := make(map[string]string)
+	for k, v := range hdr.PAXRecords {
+		if !strings.HasPrefix(k, paxXattrPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(k, paxXattrPrefix)
+		sum := sha1.Sum([]byte(v))
+		out[fmt.Sprintf("xattr.%s", name)] = fmt.Sprintf("xattr.%s=%x", name, sum)
+	}
+	return out
+}
+
+// ownershipKeywordsFromHeader synthesizes "uid"/"gid"/"uname"/"gname"
+// values straight from a tar.Header's Uid/Gid/Uname/Gname fields, the same
+// way xattrKeywordsFromPAX synthesizes xattr.* values. The generic
+// uidKeywordFunc/gidKeywordFunc/unameKeywordFunc/gnameKeywordFunc all read
+// info.Sys() as a *syscall.Stat_t (the atime/ctime pattern in
+// keywordfuncs_linux.go), which a tar-sourced os.FileInfo never yields, so
+// without this they'd silently come back empty instead of erroring --
+// exactly the failure mode that matters least for integrity verification.
+func ownershipKeywordsFromHeader(hdr *tar.Header, keywords []string) map[string]string {
+	out := make(map[string]string)
+	for _, kw := range keywords {
+		switch kw {
+		case "uid":
+			out["uid"] = fmt.Sprintf("uid=%d", hdr.Uid)
+		case "gid":
+			out["gid"] = fmt.Sprintf("gid=%d", hdr.Gid)
+		case "uname":
+			if hdr.Uname != "" {
+				out["uname"] = fmt.Sprintf("uname=%s", hdr.Uname)
+			}
+		case "gname":
+			if hdr.Gname != "" {
+				out["gname"] = fmt.Sprintf("gname=%s", hdr.Gname)
+			}
+		}
+	}
+	return out
+}
+
+func wantsXattr(keywords []string) bool {
+	for _, kw := range keywords {
+		if kw == "xattr" || kw == "xattrs" {
+			return true
+		}
+	}
+	return false
+}
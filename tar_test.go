@@ -0,0 +1,180 @@
+package mtree
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"testing"
+)
+
+func buildTar(t *testing.T, entries []*tar.Header, bodies map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range entries {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader(%s): %v", hdr.Name, err)
+		}
+		if body := bodies[hdr.Name]; body != nil {
+			if _, err := tw.Write(body); err != nil {
+				t.Fatalf("Write(%s): %v", hdr.Name, err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tw.Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestNewFromTarTimeRewrite checks that a requested "time" keyword comes
+// back as "tar_time" instead, since tar headers only carry second-precision
+// mtimes.
+func TestNewFromTarTimeRewrite(t *testing.T) {
+	raw := buildTar(t, []*tar.Header{
+		{Name: "payload", Typeflag: tar.TypeReg, Size: 4, Mode: 0644},
+	}, map[string][]byte{"payload": []byte("data")})
+
+	dh, err := NewFromTar(bytes.NewReader(raw), []string{"time"})
+	if err != nil {
+		t.Fatalf("NewFromTar: %v", err)
+	}
+	if len(dh.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(dh.Entries))
+	}
+	if _, ok := dh.Entries[0].Keywords["time"]; ok {
+		t.Fatalf("entry has a time= keyword, want it rewritten to tar_time")
+	}
+	if _, ok := dh.Entries[0].Keywords["tar_time"]; !ok {
+		t.Fatalf("entry missing tar_time keyword")
+	}
+}
+
+// TestNewFromTarXattrFromPAX checks that SCHILY.xattr.* PAX records are
+// surfaced as xattr.<name> keywords holding the SHA1 digest of the value,
+// the same format xattrKeywordFunc uses for on-disk files.
+func TestNewFromTarXattrFromPAX(t *testing.T) {
+	raw := buildTar(t, []*tar.Header{
+		{
+			Name:     "payload",
+			Typeflag: tar.TypeReg,
+			Size:     4,
+			Mode:     0644,
+			PAXRecords: map[string]string{
+				"SCHILY.xattr.user.test": "hello",
+			},
+		},
+	}, map[string][]byte{"payload": []byte("data")})
+
+	dh, err := NewFromTar(bytes.NewReader(raw), []string{"xattr"})
+	if err != nil {
+		t.Fatalf("NewFromTar: %v", err)
+	}
+
+	want := fmt.Sprintf("xattr.user.test=%x", sha1.Sum([]byte("hello")))
+	got := dh.Entries[0].Keywords["xattr.user.test"]
+	if got != want {
+		t.Fatalf("xattr.user.test = %q, want %q", got, want)
+	}
+}
+
+// TestNewFromTarOwnership checks that uid/gid/uname/gname are filled from
+// the tar header's own ownership fields, since a tar-sourced os.FileInfo
+// never satisfies the *syscall.Stat_t assertion the generic uid/gid/uname
+// KeywordFuncs rely on, and would otherwise come back silently empty.
+func TestNewFromTarOwnership(t *testing.T) {
+	raw := buildTar(t, []*tar.Header{
+		{
+			Name:     "payload",
+			Typeflag: tar.TypeReg,
+			Size:     4,
+			Mode:     0644,
+			Uid:      1000,
+			Gid:      1000,
+			Uname:    "alice",
+			Gname:    "staff",
+		},
+	}, map[string][]byte{"payload": []byte("data")})
+
+	dh, err := NewFromTar(bytes.NewReader(raw), []string{"uid", "gid", "uname", "gname"})
+	if err != nil {
+		t.Fatalf("NewFromTar: %v", err)
+	}
+
+	kws := dh.Entries[0].Keywords
+	for kw, want := range map[string]string{
+		"uid":   "uid=1000",
+		"gid":   "gid=1000",
+		"uname": "uname=alice",
+		"gname": "gname=staff",
+	} {
+		if kws[kw] != want {
+			t.Errorf("%s = %q, want %q", kw, kws[kw], want)
+		}
+	}
+}
+
+// TestValidateTarCatchesOwnershipMismatch checks that a manifest recording
+// one owner validates as mismatched against a tar whose header now carries
+// a different uid, the scenario ValidateTar exists to catch.
+func TestValidateTarCatchesOwnershipMismatch(t *testing.T) {
+	dh := &DirectoryHierarchy{Entries: []Entry{
+		{Name: "payload", Keywords: map[string]string{"uid": "uid=0"}},
+	}}
+
+	raw := buildTar(t, []*tar.Header{
+		{Name: "payload", Typeflag: tar.TypeReg, Size: 4, Mode: 0644, Uid: 1000},
+	}, map[string][]byte{"payload": []byte("data")})
+
+	mismatched, err := ValidateTar(bytes.NewReader(raw), dh, []string{"uid"})
+	if err != nil {
+		t.Fatalf("ValidateTar: %v", err)
+	}
+	if len(mismatched) != 1 || mismatched[0] != "payload" {
+		t.Fatalf("ValidateTar mismatched = %v, want [payload]", mismatched)
+	}
+}
+
+// TestValidateTarDetectsReverseMismatch guards the union-of-keys fix: a
+// keyword recorded in dh that the current tar no longer produces (e.g.
+// because a symlink was replaced by a regular file, so "link" disappears)
+// must be reported as a mismatch, not silently ignored.
+func TestValidateTarDetectsReverseMismatch(t *testing.T) {
+	dh := &DirectoryHierarchy{Entries: []Entry{
+		{Name: "payload", Keywords: map[string]string{"link": "/etc/shadow"}},
+	}}
+
+	raw := buildTar(t, []*tar.Header{
+		{Name: "payload", Typeflag: tar.TypeReg, Size: 4, Mode: 0644},
+	}, map[string][]byte{"payload": []byte("data")})
+
+	mismatched, err := ValidateTar(bytes.NewReader(raw), dh, []string{"link"})
+	if err != nil {
+		t.Fatalf("ValidateTar: %v", err)
+	}
+	if len(mismatched) != 1 || mismatched[0] != "payload" {
+		t.Fatalf("ValidateTar mismatched = %v, want [payload]", mismatched)
+	}
+}
+
+// TestValidateTarMatchesIdenticalTar checks that re-validating the exact
+// same tar a manifest was built from reports no mismatches.
+func TestValidateTarMatchesIdenticalTar(t *testing.T) {
+	raw := buildTar(t, []*tar.Header{
+		{Name: "payload", Typeflag: tar.TypeReg, Size: 4, Mode: 0644},
+	}, map[string][]byte{"payload": []byte("data")})
+
+	dh, err := NewFromTar(bytes.NewReader(raw), []string{"size", "mode"})
+	if err != nil {
+		t.Fatalf("NewFromTar: %v", err)
+	}
+
+	mismatched, err := ValidateTar(bytes.NewReader(raw), dh, []string{"size", "mode"})
+	if err != nil {
+		t.Fatalf("ValidateTar: %v", err)
+	}
+	if len(mismatched) != 0 {
+		t.Fatalf("ValidateTar mismatched = %v, want none", mismatched)
+	}
+}
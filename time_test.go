@@ -0,0 +1,79 @@
+package mtree
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// fakeFileInfo is a minimal os.FileInfo stand-in so timeKeywordFunc can be
+// exercised without touching the filesystem.
+type fakeFileInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (fi fakeFileInfo) Name() string       { return fi.name }
+func (fi fakeFileInfo) Size() int64        { return 0 }
+func (fi fakeFileInfo) Mode() os.FileMode  { return 0644 }
+func (fi fakeFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fakeFileInfo) IsDir() bool        { return false }
+func (fi fakeFileInfo) Sys() interface{}   { return nil }
+
+// TestTimeKeywordRoundTrip guards against the t%(t/1e9) fractional-seconds
+// bug: it round-trips a known ModTime through create (timeKeywordFunc) ->
+// parse (Parse) -> validate (comparing the parsed value back against what
+// create produced).
+func TestTimeKeywordRoundTrip(t *testing.T) {
+	mt := time.Date(2026, 7, 27, 12, 34, 56, 123456789, time.UTC)
+	info := fakeFileInfo{name: "file", modTime: mt}
+
+	wantSec, wantNsec := splitUnixNano(mt.UnixNano())
+	want := fmt.Sprintf("time=%d.%09d", wantSec, wantNsec)
+
+	created, err := timeKeywordFunc("file", info, nil)
+	if err != nil {
+		t.Fatalf("timeKeywordFunc: %v", err)
+	}
+	if created != want {
+		t.Fatalf("timeKeywordFunc(%s) = %q, want %q", mt, created, want)
+	}
+
+	dh := &DirectoryHierarchy{Entries: []Entry{
+		{Name: "file", Keywords: map[string]string{"time": created[len("time="):]}},
+	}}
+
+	var buf bytes.Buffer
+	if _, err := dh.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(parsed.Entries) != 1 {
+		t.Fatalf("Parse: got %d entries, want 1", len(parsed.Entries))
+	}
+
+	got := "time=" + parsed.Entries[0].Keywords["time"]
+	if got != want {
+		t.Fatalf("round-tripped time = %q, want %q", got, want)
+	}
+}
+
+// TestTimeKeywordZero guards the t==0 (Unix epoch) edge case that the
+// original "if t == 0" special-case was carved out for.
+func TestTimeKeywordZero(t *testing.T) {
+	info := fakeFileInfo{name: "file", modTime: time.Unix(0, 0).UTC()}
+
+	got, err := timeKeywordFunc("file", info, nil)
+	if err != nil {
+		t.Fatalf("timeKeywordFunc: %v", err)
+	}
+	if want := "time=0.000000000"; got != want {
+		t.Fatalf("timeKeywordFunc(epoch) = %q, want %q", got, want)
+	}
+}